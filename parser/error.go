@@ -0,0 +1,191 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseError is returned by the combinators in this package instead of
+// a bare sentinel like ErrNoMatch: it carries the Position of the
+// failure, what was Expected there (zero or more human-readable
+// descriptions), what was Actually found, and the lower-level Cause
+// (often ErrNoMatch, RuneError, or io.EOF) for errors.Is/errors.As.
+type ParseError struct {
+	Position Position
+	Expected []string
+	Actual   string
+	Cause    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parser: at %v: %s", e.Position, e.message())
+}
+
+// message describes the failure without the Position, for FormatError
+// to combine with a "line:column:" prefix of its own.
+func (e *ParseError) message() string {
+	switch len(e.Expected) {
+	case 0:
+		return fmt.Sprintf("unexpected %s", e.Actual)
+	case 1:
+		return fmt.Sprintf("expected %s, got %s", e.Expected[0], e.Actual)
+	default:
+		return fmt.Sprintf("expected one of %s, got %s", strings.Join(e.Expected, ", "), e.Actual)
+	}
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Cause
+}
+
+// newParseError builds a *ParseError for a failure observed at s,
+// with expected naming what the caller was looking for (nil if it has
+// no useful name of its own, e.g. ConditionRune's predicate) and cause
+// the lower-level error that triggered the failure.
+func newParseError(s State, expected []string, cause error) *ParseError {
+	return &ParseError{
+		Position: s.Position(),
+		Expected: expected,
+		Actual:   actualDescription(s),
+		Cause:    cause,
+	}
+}
+
+// actualDescription describes the input at s for use in a ParseError,
+// the same way go/scanner reports "found ...". It distinguishes the
+// ways PeekRune can fail instead of collapsing them all to "EOF", so a
+// ParseError's Actual reflects what really stopped the parse.
+func actualDescription(s State) string {
+	r, err := s.PeekRune()
+	switch {
+	case err == nil:
+		return fmt.Sprintf("%q", string(r))
+	case errors.Is(err, io.EOF):
+		return "EOF"
+	case errors.Is(err, ErrCommitted):
+		return "a position already committed past"
+	default:
+		var re RuneError
+		if errors.As(err, &re) {
+			return "invalid UTF-8 encoding"
+		}
+		return err.Error()
+	}
+}
+
+// Label runs p and, if it fails, wraps the error in a *ParseError
+// naming name as what was expected. If the underlying failure already
+// carries a *ParseError (directly, or as the deepest alternative of a
+// *ChoiceError), Label reports that failure's Position and Actual
+// instead of s's own, so a partial match further into p still reports
+// where things actually went wrong; the original error is kept as
+// Cause either way so it remains reachable via errors.Unwrap/FormatError.
+func Label[T any](name string, p Parser[T]) Parser[T] {
+	return func(s State) (T, State, error) {
+		t, next, err := p(s)
+		if err == nil {
+			return t, next, nil
+		}
+		var zero T
+		pos, actual := s.Position(), actualDescription(s)
+		if pe := deepestParseError(err); pe != nil {
+			pos, actual = pe.Position, pe.Actual
+		}
+		return zero, s, &ParseError{Position: pos, Expected: []string{name}, Actual: actual, Cause: err}
+	}
+}
+
+// deepestParseError finds the *ParseError reached furthest into the
+// input among err and (if err is a *ChoiceError) all of its children,
+// recursively. It returns nil if none of them wraps a *ParseError.
+func deepestParseError(err error) *ParseError {
+	if choiceErr, ok := err.(*ChoiceError); ok {
+		return choiceErr.deepest()
+	}
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		return pe
+	}
+	return nil
+}
+
+// deepest returns the *ParseError among e.Errors (and their own
+// *ChoiceError children, recursively) with the greatest Position
+// offset: the branch that got furthest before failing is the one
+// worth reporting.
+func (e *ChoiceError) deepest() *ParseError {
+	var best *ParseError
+	for _, err := range e.Errors {
+		pe := deepestParseError(err)
+		if pe == nil {
+			continue
+		}
+		if best == nil || pe.Position.Offset > best.Position.Offset {
+			best = pe
+		}
+	}
+	return best
+}
+
+// merged unions the Expected of every child *ParseError that reached
+// the greatest Position offset among e.Errors, Parsec-style: once one
+// alternative gets further than the others before failing, that's the
+// error worth reporting; ties union what every alternative at that
+// depth was looking for. It returns nil if none of e.Errors wraps a
+// *ParseError.
+func (e *ChoiceError) merged() *ParseError {
+	var deepest *ParseError
+	var expected []string
+	for _, err := range e.Errors {
+		pe := deepestParseError(err)
+		if pe == nil {
+			continue
+		}
+		switch {
+		case deepest == nil || pe.Position.Offset > deepest.Position.Offset:
+			deepest = pe
+			expected = append([]string(nil), pe.Expected...)
+		case pe.Position.Offset == deepest.Position.Offset:
+			expected = unionStrings(expected, pe.Expected)
+		}
+	}
+	if deepest == nil {
+		return nil
+	}
+	return &ParseError{Position: deepest.Position, Expected: expected, Actual: deepest.Actual}
+}
+
+func unionStrings(a, b []string) []string {
+	seen := make(map[string]bool, len(a))
+	for _, s := range a {
+		seen[s] = true
+	}
+	for _, s := range b {
+		if !seen[s] {
+			a = append(a, s)
+			seen[s] = true
+		}
+	}
+	return a
+}
+
+// FormatError renders err as a caret-underlined snippet of source, the
+// way go/scanner reports syntax errors. If err doesn't wrap a
+// *ParseError (directly, or as the deepest alternative of a
+// *ChoiceError), it falls back to err.Error().
+func FormatError(err error, source []byte) string {
+	pe := deepestParseError(err)
+	if pe == nil {
+		return err.Error()
+	}
+	lines := bytes.Split(source, []byte("\n"))
+	var lineText string
+	if i := pe.Position.Line - 1; i >= 0 && i < len(lines) {
+		lineText = string(lines[i])
+	}
+	caret := strings.Repeat(" ", max(pe.Position.Column-1, 0)) + "^"
+	return fmt.Sprintf("%d:%d: %s\n%s\n%s", pe.Position.Line, pe.Position.Column, pe.message(), lineText, caret)
+}
@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestExactString_ParseErrorOnMismatch(t *testing.T) {
+	_, err := DoParse(ExactString("foo"), "bar")
+
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("err = %v WANT *ParseError", err)
+	}
+	if !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("err = %v WANT ErrNoMatch in chain", err)
+	}
+	if len(pe.Expected) != 1 || pe.Expected[0] != `"foo"` {
+		t.Fatalf("pe.Expected = %v WANT %v", pe.Expected, []string{`"foo"`})
+	}
+}
+
+func TestExactString_ParseErrorOnEOF(t *testing.T) {
+	_, err := DoParse(ExactString("foo"), "fo")
+
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("err = %v WANT io.EOF in chain", err)
+	}
+}
+
+func TestConditionRune_ParseErrorHasNoExpectedOfItsOwn(t *testing.T) {
+	p := ConditionRune(func(r rune) bool { return r == 'a' })
+
+	_, err := DoParse(p, "b")
+
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("err = %v WANT *ParseError", err)
+	}
+	if len(pe.Expected) != 0 {
+		t.Fatalf("pe.Expected = %v WANT empty", pe.Expected)
+	}
+}
+
+func TestLabel_NamesAnUnlabeledFailure(t *testing.T) {
+	p := Label("digit", ConditionRune(func(r rune) bool { return r >= '0' && r <= '9' }))
+
+	_, err := DoParse(p, "x")
+
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("err = %v WANT *ParseError", err)
+	}
+	if len(pe.Expected) != 1 || pe.Expected[0] != "digit" {
+		t.Fatalf("pe.Expected = %v WANT %v", pe.Expected, []string{"digit"})
+	}
+}
+
+func TestLabel_WrapsAPartialMatchFailure(t *testing.T) {
+	p := Label("keyword", ExactString("foobar"))
+
+	_, err := DoParse(p, "foom")
+
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("err = %v WANT *ParseError", err)
+	}
+	if len(pe.Expected) != 1 || pe.Expected[0] != "keyword" {
+		t.Fatalf("pe.Expected = %v WANT %v", pe.Expected, []string{"keyword"})
+	}
+	if !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("err = %v WANT ErrNoMatch in chain", err)
+	}
+}
+
+func TestChoice_MergesExpectedOfEquallyDeepAlternatives(t *testing.T) {
+	p := Choice(Label("a", ExactString("a")), Label("b", ExactString("b")))
+
+	_, err := DoParse(p, "c")
+
+	if err.Error() != `parser: at {0 1 1}: expected one of a, b, got "c"` {
+		t.Fatalf("err.Error() = %q", err.Error())
+	}
+}
+
+func TestChoice_SurfacesOnlyTheDeepestAlternativeError(t *testing.T) {
+	p := Choice(Try(Label("keyword", ExactString("foobar"))), Label("other", ExactString("baz")))
+
+	_, err := DoParse(p, "foom")
+
+	var choiceErr *ChoiceError
+	if !errors.As(err, &choiceErr) {
+		t.Fatalf("err = %v WANT *ChoiceError", err)
+	}
+	merged := choiceErr.merged()
+	if merged == nil {
+		t.Fatal("merged() = nil")
+	}
+	if len(merged.Expected) != 1 || merged.Expected[0] != "keyword" {
+		t.Fatalf("merged.Expected = %v WANT %v", merged.Expected, []string{"keyword"})
+	}
+}
+
+func TestConditionRune_ParseErrorOnInvalidUTF8DescribesTheEncodingNotEOF(t *testing.T) {
+	p := ConditionRune(func(r rune) bool { return r == 'a' })
+
+	_, err := DoParse(p, "\xff")
+
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("err = %v WANT *ParseError", err)
+	}
+	if pe.Actual != "invalid UTF-8 encoding" {
+		t.Fatalf("pe.Actual = %q WANT %q", pe.Actual, "invalid UTF-8 encoding")
+	}
+}
+
+func TestFormatError_UnderlinesTheFailurePosition(t *testing.T) {
+	source := "1 + x"
+	isDigit := func(r rune) bool { return r >= '0' && r <= '9' }
+	p := AndThen(ExactString("1 + "), func(_ Empty) Parser[rune] {
+		return Label("number", ConditionRune(isDigit))
+	})
+
+	_, err := DoParse(p, source)
+	if err == nil {
+		t.Fatal("err = nil")
+	}
+
+	formatted := FormatError(err, []byte(source))
+	want := "1:5: expected number, got \"x\"\n1 + x\n    ^"
+	if formatted != want {
+		t.Fatalf("formatted = %q WANT %q", formatted, want)
+	}
+}
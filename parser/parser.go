@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"unsafe"
 )
 
 var ErrNoMatch = fmt.Errorf("parser: no match")
@@ -11,11 +13,7 @@ type Parser[T any] func(State) (T, State, error)
 type Empty struct{}
 
 func DoParse[T any](parser Parser[T], input string) (T, error) {
-	state := State{
-		buffer: []byte(input),
-		offset: 0,
-	}
-	result, _, err := parser(state)
+	result, _, err := parser(NewStateString(input))
 	return result, err
 }
 
@@ -31,17 +29,19 @@ func AndThen[T any, U any](parser Parser[T], handler func(T) Parser[U]) Parser[U
 }
 
 func ExactString(token string) Parser[Empty] {
+	expected := []string{fmt.Sprintf("%q", token)}
 	return func(s State) (Empty, State, error) {
 		next := s
 		for _, tokenRune := range token {
 			var r rune
 			var err error
+			failedAt := next
 			r, next, err = next.Rune()
 			if err != nil {
-				return Empty{}, s, err
+				return Empty{}, s, newParseError(failedAt, expected, err)
 			}
 			if tokenRune != r {
-				return Empty{}, s, ErrNoMatch
+				return Empty{}, s, newParseError(failedAt, expected, ErrNoMatch)
 			}
 		}
 		return Empty{}, next, nil
@@ -49,17 +49,19 @@ func ExactString(token string) Parser[Empty] {
 }
 
 func ExactBytes(token []byte) Parser[Empty] {
+	expected := []string{fmt.Sprintf("%q", token)}
 	return func(s State) (Empty, State, error) {
 		next := s
 		for _, tokenByte := range token {
 			var b byte
 			var err error
+			failedAt := next
 			b, next, err = next.Byte()
 			if err != nil {
-				return Empty{}, s, err
+				return Empty{}, s, newParseError(failedAt, expected, err)
 			}
 			if tokenByte != b {
-				return Empty{}, s, ErrNoMatch
+				return Empty{}, s, newParseError(failedAt, expected, ErrNoMatch)
 			}
 		}
 		return Empty{}, next, nil
@@ -74,7 +76,7 @@ func Sequence2[T, U, R any](tParser Parser[T], uParser Parser[U], mapper func(T,
 			return zero, s, err
 		}
 		var u U
-		u, next, err = uParser(s)
+		u, next, err = uParser(next)
 		if err != nil {
 			var zero R
 			return zero, s, err
@@ -82,7 +84,7 @@ func Sequence2[T, U, R any](tParser Parser[T], uParser Parser[U], mapper func(T,
 		r, err := mapper(t, u)
 		if err != nil {
 			var zero R
-			return zero, s, err
+			return zero, s, newParseError(next, nil, err)
 		}
 		return r, next, nil
 	}
@@ -92,38 +94,217 @@ func ConditionRune(cond func(rune) bool) Parser[rune] {
 	return func(s State) (rune, State, error) {
 		r, next, err := s.Rune()
 		if err != nil {
-			return 0, s, err
+			return 0, s, newParseError(s, nil, err)
 		}
 		if !cond(r) {
-			return 0, s, ErrNoMatch
+			return 0, s, newParseError(s, nil, ErrNoMatch)
 		}
 		return r, next, nil
 	}
 }
 
+// Commit runs p and, if it succeeds, marks the resulting offset as
+// unrecoverable on a NewStreamingStateReader source: the buffers of
+// *data nodes entirely before it become eligible for garbage
+// collection, which bounds memory use when parsing a long-running
+// io.Reader. The nodes themselves stay linked from the first node of
+// the chain; only their buffers are released. A State in a reclaimed
+// node that's later used to read gets ErrCommitted; a State before the
+// committed offset but in a node that is still retained (the one the
+// committed offset falls in) reads as before. Commit is a no-op on
+// non-streaming sources.
+func Commit[T any](p Parser[T]) Parser[T] {
+	return func(s State) (T, State, error) {
+		t, next, err := p(s)
+		if err != nil {
+			var zero T
+			return zero, s, err
+		}
+		if next.owner != nil && next.inTry == 0 {
+			next.owner.commit(next.offset, next.line, next.column)
+		}
+		return t, next, nil
+	}
+}
+
+// Choice tries each parser in order and returns the result of the
+// first one that succeeds. An alternative is only attempted if the
+// previous one failed without consuming input (its returned State has
+// the same offset as the input State); a parser that consumes input
+// before failing is assumed to mean business, so its error is
+// returned immediately rather than backtracking. Wrap an alternative
+// in Try to opt it into arbitrary-lookahead backtracking instead.
+//
+// If every alternative fails without consuming input, Choice returns
+// a *ChoiceError aggregating all of their errors.
+func Choice[T any](parsers ...Parser[T]) Parser[T] {
+	return func(s State) (T, State, error) {
+		var errs []error
+		for _, p := range parsers {
+			t, next, err := p(s)
+			if err == nil {
+				return t, next, nil
+			}
+			if next.offset != s.offset {
+				return t, next, err
+			}
+			errs = append(errs, err)
+		}
+		var zero T
+		return zero, s, &ChoiceError{Position: s.Position(), Errors: errs}
+	}
+}
+
+// ChoiceError is returned by Choice when every alternative fails
+// without consuming input.
+type ChoiceError struct {
+	Position Position
+	Errors   []error
+}
+
+func (e *ChoiceError) Error() string {
+	if merged := e.merged(); merged != nil {
+		return merged.Error()
+	}
+	return fmt.Sprintf("parser: no alternative matched at %v: %v", e.Position, errors.Join(e.Errors...))
+}
+
+func (e *ChoiceError) Unwrap() []error {
+	return e.Errors
+}
+
+// Try runs p and, if it fails, restores the input State regardless of
+// how much of it p consumed before failing. This lets Choice branch
+// on alternatives that need arbitrary lookahead instead of just the
+// single-token lookahead Choice gives alternatives for free. While p
+// is running, Commit is inhibited: a Commit nested inside Try never
+// takes effect, since Try may still decide to backtrack past it. Wrap
+// the whole Try (or higher) in Commit to commit once it succeeds.
+func Try[T any](p Parser[T]) Parser[T] {
+	return func(s State) (T, State, error) {
+		attempt := s
+		attempt.inTry++
+		t, next, err := p(attempt)
+		if err != nil {
+			var zero T
+			return zero, s, err
+		}
+		next.inTry = s.inTry
+		return t, next, nil
+	}
+}
+
+// OptionalResult is the result of Optional: Ok reports whether the
+// wrapped parser matched, and Value holds its result when it did.
+type OptionalResult[T any] struct {
+	Value T
+	Ok    bool
+}
+
+// Optional makes p's failure-without-consuming-input into a
+// successful empty match instead of propagating the error. If p
+// consumes input before failing, that error is still propagated: use
+// Try(p) if p should backtrack first.
+func Optional[T any](p Parser[T]) Parser[OptionalResult[T]] {
+	return func(s State) (OptionalResult[T], State, error) {
+		t, next, err := p(s)
+		if err != nil {
+			if next.offset != s.offset {
+				var zero OptionalResult[T]
+				return zero, s, err
+			}
+			return OptionalResult[T]{}, s, nil
+		}
+		return OptionalResult[T]{Value: t, Ok: true}, next, nil
+	}
+}
+
+// Many applies p repeatedly until it fails without consuming input,
+// collecting the results. It never itself fails: zero matches is a
+// successful empty slice. If p fails having consumed input, that
+// error is propagated rather than treated as the end of the run.
+func Many[T any](p Parser[T]) Parser[[]T] {
+	return func(s State) ([]T, State, error) {
+		var results []T
+		cur := s
+		for {
+			t, next, err := p(cur)
+			if err != nil {
+				if next.offset != cur.offset {
+					return nil, s, err
+				}
+				break
+			}
+			results = append(results, t)
+			cur = next
+		}
+		return results, cur, nil
+	}
+}
+
+// Many1 is like Many but requires at least one match, failing with
+// ErrNoMatch otherwise.
+func Many1[T any](p Parser[T]) Parser[[]T] {
+	return func(s State) ([]T, State, error) {
+		results, next, err := Many(p)(s)
+		if err != nil {
+			return nil, s, err
+		}
+		if len(results) == 0 {
+			return nil, s, ErrNoMatch
+		}
+		return results, next, nil
+	}
+}
+
+// GetString returns the exact input consumed by parser as a string,
+// instead of parser's own result. For sources backed by a single
+// data node (e.g. NewStateString, or the *strings.Reader/*bytes.Reader
+// fast paths in NewStateReaderSize) this does not allocate: it views
+// the already-read bytes directly via unsafe.String. Because of that,
+// the returned string aliases the source's internal buffer: it must
+// not be passed anywhere that could mutate its bytes (e.g. through
+// further unsafe conversion to a []byte). A later Commit only drops the
+// source's own reference to that buffer, so the returned string keeps
+// it alive and stays valid. If parser's span has already been
+// reclaimed by an inner Commit, GetString returns ErrCommitted.
 func GetString[T any](parser Parser[T]) Parser[string] {
 	return func(s State) (string, State, error) {
-		start := s.offset
 		_, next, err := parser(s)
 		if err != nil {
 			return "", s, err
 		}
-		end := s.offset
-		return string(s.buffer[start:end]), next, nil
+		b, err := keepBytes(s, next)
+		if err != nil {
+			return "", s, err
+		}
+		if len(b) == 0 {
+			return "", next, nil
+		}
+		return unsafe.String(&b[0], len(b)), next, nil
 	}
 }
 
+// GetBytes returns the exact input consumed by parser as a []byte,
+// instead of parser's own result. When that span falls within a
+// single data node the returned slice aliases the source's internal
+// buffer rather than copying it, so callers must treat it as
+// read-only: mutating it corrupts whatever the source reads next. A
+// later Commit only drops the source's own reference to that buffer,
+// so the returned slice keeps it alive and stays valid. If
+// parser's span has already been reclaimed by an inner Commit,
+// GetBytes returns ErrCommitted.
 func GetBytes[T any](parser Parser[T]) Parser[[]byte] {
 	return func(s State) ([]byte, State, error) {
-		start := s.offset
 		_, next, err := parser(s)
 		if err != nil {
 			return nil, s, err
 		}
-		end := s.offset
-		result := make([]byte, end-start)
-		copy(result, s.buffer[start:end])
-		return result, next, nil
+		b, err := keepBytes(s, next)
+		if err != nil {
+			return nil, s, err
+		}
+		return b, next, nil
 	}
 }
 
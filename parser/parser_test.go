@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"errors"
 	"strings"
 	"testing"
 )
@@ -28,18 +29,201 @@ func TestAndThen_OK(t *testing.T) {
 	}
 }
 
-func TestCollectBytes(t *testing.T) {
-	const data = "1234abcd5678efgh90"
-	p := CollectBytes(ExactString(data))
-	state := NewStateReaderSize(strings.NewReader(data), 8)
+func TestSequence2_SequencesBothParsersInOrder(t *testing.T) {
+	isDigit := func(r rune) bool { return r >= '0' && r <= '9' }
+	p := Sequence2(
+		GetString(ExactString("foo")),
+		GetString(Many1(ConditionRune(isDigit))),
+		func(prefix, digits string) (string, error) {
+			return prefix + ":" + digits, nil
+		},
+	)
 
-	result, err := DoParseState(p, state)
+	result, err := DoParse(p, "foo123bar")
 	if err != nil {
 		t.Fatal(err)
 	}
-	if string(result) != data {
-		t.Fatal()
+	if result != "foo:123" {
+		t.Fatalf("result = %q WANT %q", result, "foo:123")
+	}
+}
+
+func TestSequence2_WrapsMapperErrorInParseError(t *testing.T) {
+	p := Sequence2(
+		GetString(ExactString("foo")),
+		GetString(ExactString("bar")),
+		func(_, _ string) (string, error) {
+			return "", errors.New("mapper rejected the combination")
+		},
+	)
+
+	_, err := DoParse(p, "foobar")
+
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("err = %v WANT *ParseError", err)
 	}
 }
 
 // TODO more collect tests
+
+func TestChoice_FirstMatch(t *testing.T) {
+	p := Choice(ExactString("foo"), ExactString("bar"))
+
+	_, err := DoParse(p, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChoice_FallsThroughOnNoConsumeFailure(t *testing.T) {
+	p := Choice(ExactString("foo"), ExactString("bar"))
+
+	_, err := DoParse(p, "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChoice_AllFail(t *testing.T) {
+	p := Choice(ExactString("foo"), ExactString("bar"))
+
+	_, err := DoParse(p, "baz")
+	var choiceErr *ChoiceError
+	if !errors.As(err, &choiceErr) {
+		t.Fatalf("err = %v WANT *ChoiceError", err)
+	}
+	if len(choiceErr.Errors) != 2 {
+		t.Fatalf("len(choiceErr.Errors) = %v WANT 2", len(choiceErr.Errors))
+	}
+}
+
+// consumeThenFail reads one rune and then fails, returning the
+// advanced State rather than resetting to the input State. It models
+// a parser that doesn't follow this package's usual convention of
+// resetting to the input State on failure, which is what Choice's
+// offset check and Try's explicit restore are for.
+func consumeThenFail(s State) (Empty, State, error) {
+	_, next, err := s.Rune()
+	if err != nil {
+		return Empty{}, s, err
+	}
+	return Empty{}, next, ErrNoMatch
+}
+
+func TestChoice_ConsumingFailureIsNotRetried(t *testing.T) {
+	p := Choice(consumeThenFail, ExactString("ab"))
+
+	_, err := DoParse(p, "ab")
+	if !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("err = %v WANT ErrNoMatch", err)
+	}
+}
+
+func TestTry_BacktracksAfterConsuming(t *testing.T) {
+	p := Choice(Try(consumeThenFail), ExactString("ab"))
+
+	_, err := DoParse(p, "ab")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOptional_NoMatch(t *testing.T) {
+	p := Optional(ExactString("foo"))
+
+	result, err := DoParse(p, "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Ok {
+		t.Fatalf("result = %+v WANT Ok = false", result)
+	}
+}
+
+func TestOptional_Match(t *testing.T) {
+	p := Optional(ExactString("foo"))
+
+	result, err := DoParse(p, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Ok {
+		t.Fatalf("result = %+v WANT Ok = true", result)
+	}
+}
+
+func TestMany_ZeroMatches(t *testing.T) {
+	p := Many(ExactString("ab"))
+
+	result, err := DoParse(p, "xyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 0 {
+		t.Fatalf("result = %v WANT empty", result)
+	}
+}
+
+func TestMany_CollectsAllMatches(t *testing.T) {
+	p := Many(ConditionRune(func(r rune) bool { return r == 'a' }))
+
+	result, err := DoParse(p, "aaab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 3 {
+		t.Fatalf("result = %v WANT 3 matches", result)
+	}
+}
+
+func TestMany1_RequiresOneMatch(t *testing.T) {
+	p := Many1(ConditionRune(func(r rune) bool { return r == 'a' }))
+
+	_, err := DoParse(p, "bbb")
+	if !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("err = %v WANT ErrNoMatch", err)
+	}
+}
+
+func TestGetBytes_NoAllocationForStringsReader(t *testing.T) {
+	const data = "1234abcd5678efgh90"
+	p := GetBytes(ExactString(data))
+	state := NewStateReaderSize(strings.NewReader(data), 8)
+
+	var result []byte
+	allocs := testing.AllocsPerRun(10, func() {
+		var err error
+		result, _, err = p(state)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	if string(result) != data {
+		t.Fatal(result)
+	}
+	if allocs != 0 {
+		t.Fatalf("allocs = %v WANT 0", allocs)
+	}
+}
+
+func TestGetString_NoAllocationForStringsReader(t *testing.T) {
+	const data = "1234abcd5678efgh90"
+	p := GetString(ExactString(data))
+	state := NewStateReaderSize(strings.NewReader(data), 8)
+
+	var result string
+	allocs := testing.AllocsPerRun(10, func() {
+		var err error
+		result, _, err = p(state)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	if result != data {
+		t.Fatal(result)
+	}
+	if allocs != 0 {
+		t.Fatalf("allocs = %v WANT 0", allocs)
+	}
+}
@@ -1,11 +1,9 @@
 package parser
 
 import (
-	"bytes"
 	"fmt"
 	"io"
 	"log"
-	"strings"
 	"unicode/utf8"
 )
 
@@ -17,6 +15,21 @@ func (e RuneError) Error() string {
 	return fmt.Sprintf("parser: invalid rune at position %v", e.Position)
 }
 
+// invalidRuneError wraps a RuneError at s's Position in a *ParseError
+// like the package's other failure sites. It doesn't go through
+// newParseError/actualDescription, which peek the next rune via
+// s.Rune() itself: calling back into Rune() from here, at the exact
+// offset Rune() just found undecodable, would recurse forever.
+func invalidRuneError(s State) error {
+	return &ParseError{Position: s.Position(), Actual: "invalid UTF-8 encoding", Cause: RuneError{s.Position()}}
+}
+
+// ErrCommitted is returned when a read is attempted against a *data
+// node that Commit has already reclaimed. It means the State being
+// read from was captured before a commit point and can no longer be
+// resumed.
+var ErrCommitted = fmt.Errorf("parser: read past committed offset")
+
 type State struct {
 	data  *data
 	datap int
@@ -24,17 +37,28 @@ type State struct {
 	offset int64
 	line   int
 	column int
+
+	// head is the first *data node of the whole chain, kept around so
+	// SeekOffset can walk forward from it to reach any previously
+	// observed offset, even one behind the current node.
+	head  *data
+	owner *streamOwner
+	inTry int
 }
 
 func NewStateString(s string) State {
+	d := newDataString(s)
 	return State{
-		data: newDataString(s),
+		data: d,
+		head: d,
 	}
 }
 
 func NewStateBytes(b []byte) State {
+	d := newDataBytes(b)
 	return State{
-		data: newDataBytes(b),
+		data: d,
+		head: d,
 	}
 }
 
@@ -44,8 +68,25 @@ func NewStateReader(r io.Reader) State {
 
 func NewStateReaderSize(r io.Reader, size int) State {
 	size = max(size, minDataSize)
+	d := newDataReaderSize(r, size)
+	return State{
+		data: d,
+		head: d,
+	}
+}
+
+// NewStreamingStateReader is like NewStateReaderSize, but the
+// returned State's *data chain is reclaimable: wrap parsers with
+// Commit to let the buffers of nodes before the committed offset be
+// garbage collected, bounding memory use when parsing an open-ended
+// io.Reader (a log tailer, a long-lived connection, ...).
+func NewStreamingStateReader(r io.Reader, size int) State {
+	size = max(size, minDataSize)
+	d := newDataReaderSize(r, size)
 	return State{
-		data: newDataReaderSize(r, size),
+		data:  d,
+		head:  d,
+		owner: &streamOwner{head: d, committed: -1},
 	}
 }
 
@@ -66,18 +107,21 @@ func (s State) Column() int {
 }
 
 func (s State) Rune() (rune, State, error) {
+	if s.data.reclaimed && s.datap < int(s.data.end-s.data.start) {
+		return 0, s, ErrCommitted
+	}
 	if s.datap < len(s.data.buf) {
 		r, rs := utf8.DecodeRune(s.data.buf[s.datap:])
-		// TODO what did it think the error was without the rs == 1 check.
-		if r == utf8.RuneError {
+		// rs == 1 is DecodeRune's own signal for "couldn't decode a
+		// rune here": a legitimately-encoded U+FFFD also decodes to
+		// utf8.RuneError, but with rs == utf8.RuneLen(utf8.RuneError)
+		// (3), so it must not be treated as invalid input.
+		if r == utf8.RuneError && rs == 1 {
 			if s.data.r == nil {
-				return 0, s, RuneError{s.Position()}
+				return 0, s, invalidRuneError(s)
 			}
 			// There is more data to read.
-			if s.data.next == nil {
-				// There is more data to read and next to initialize.
-				s.data.next = newDataReaderSize(s.data.r, cap(s.data.buf))
-			}
+			s.data.ensureNext()
 			// Now have more data in next to use for decode rune.
 			// minDataSize means run has to fit in s.data and s.data.next.
 			nextDataPMax := min(len(s.data.next.buf), 6)
@@ -85,9 +129,8 @@ func (s State) Rune() (rune, State, error) {
 			runeBytes = append(runeBytes, s.data.buf[s.datap:]...)
 			runeBytes = append(runeBytes, s.data.next.buf[:nextDataPMax]...)
 			r, rs = utf8.DecodeRune(runeBytes)
-			// TODO what did it think the error was without the rs == 1 check.
-			if r == utf8.RuneError {
-				return 0, s, RuneError{s.Position()}
+			if r == utf8.RuneError && rs == 1 {
+				return 0, s, invalidRuneError(s)
 			}
 			nextState := s.nextDataState()
 			nextState.datap = rs - (len(s.data.buf) - s.datap)
@@ -104,7 +147,7 @@ func (s State) Rune() (rune, State, error) {
 
 	if s.data.r != nil && s.data.next == nil {
 		// There is more data to read and next to initialize.
-		s.data.next = newDataReaderSize(s.data.r, cap(s.data.buf))
+		s.data.ensureNext()
 		return s.nextDataState().Rune()
 	}
 
@@ -118,6 +161,9 @@ func (s State) Rune() (rune, State, error) {
 }
 
 func (s State) Byte() (byte, State, error) {
+	if s.data.reclaimed && s.datap < int(s.data.end-s.data.start) {
+		return 0, s, ErrCommitted
+	}
 	if s.datap < len(s.data.buf) {
 		// Have a byte available in data's buffer.
 		b := s.data.buf[s.datap]
@@ -129,7 +175,7 @@ func (s State) Byte() (byte, State, error) {
 
 	if s.data.r != nil && s.data.next == nil {
 		// There is more data to read and next to initialize.
-		s.data.next = newDataReaderSize(s.data.r, cap(s.data.buf))
+		s.data.ensureNext()
 		return s.nextDataState().Byte()
 	}
 
@@ -145,13 +191,99 @@ func (s State) nextDataState() State {
 	if s.data.next == nil {
 		panic("s.data.next is nil")
 	}
+	if !s.data.next.posCached {
+		s.data.next.posCached = true
+		s.data.next.lineAtStart = s.line
+		s.data.next.colAtStart = s.column
+	}
 	return State{
 		data:   s.data.next,
 		datap:  0,
 		offset: s.offset,
 		line:   s.line,
 		column: s.column,
+		head:   s.head,
+		owner:  s.owner,
+		inTry:  s.inTry,
+	}
+}
+
+// PeekRune reports the next rune without advancing s.
+func (s State) PeekRune() (rune, error) {
+	r, _, err := s.Rune()
+	return r, err
+}
+
+// PeekBytes reports the next n bytes without advancing s. If fewer
+// than n bytes are available it returns what it could read along with
+// the error (typically io.EOF) that stopped it, matching the partial
+// read convention of io.Reader.
+func (s State) PeekBytes(n int) ([]byte, error) {
+	buf := make([]byte, 0, n)
+	cur := s
+	for len(buf) < n {
+		var b byte
+		var err error
+		b, cur, err = cur.Byte()
+		if err != nil {
+			return buf, err
+		}
+		buf = append(buf, b)
+	}
+	return buf, nil
+}
+
+// SeekOffset returns a new State positioned at offset, which must be
+// an offset s or an earlier State derived from the same input has
+// already read up to. It's validated against the retained *data
+// chain: seeking into a node that Commit has since reclaimed returns
+// ErrCommitted, and seeking past what's been read returns io.EOF.
+func (s State) SeekOffset(offset int64) (State, error) {
+	if offset < 0 {
+		return State{}, fmt.Errorf("parser: seek offset %d is negative", offset)
 	}
+	line, column := 0, 0
+	for d := s.head; d != nil; d = d.next {
+		if d.posCached {
+			line, column = d.lineAtStart, d.colAtStart
+		}
+		atThisNode := offset >= d.start && (offset < d.end || d.next == nil) && offset <= d.end
+		if atThisNode {
+			if d.reclaimed {
+				return State{}, ErrCommitted
+			}
+			datap := int(offset - d.start)
+			endLine, endColumn := advancePosition(line, column, d.buf[:datap])
+			return State{
+				data:   d,
+				datap:  datap,
+				offset: offset,
+				line:   endLine,
+				column: endColumn,
+				head:   s.head,
+				owner:  s.owner,
+				inTry:  s.inTry,
+			}, nil
+		}
+	}
+	return State{}, io.EOF
+}
+
+// advancePosition returns the (line, column) reached after consuming
+// buf starting from (line, column), counting newlines the same way
+// State.consume does.
+func advancePosition(line, column int, buf []byte) (int, int) {
+	for len(buf) > 0 {
+		r, size := utf8.DecodeRune(buf)
+		buf = buf[size:]
+		if r == '\n' {
+			line++
+			column = 0
+		} else {
+			column++
+		}
+	}
+	return line, column
 }
 
 func (s State) consume(count int, v rune) (State, error) {
@@ -165,24 +297,38 @@ func (s State) consume(count int, v rune) (State, error) {
 	return s, nil
 }
 
-func keepBytes(start, end State) []byte {
+// keepBytes returns the bytes consumed between start and end. When
+// both fall within the same node it returns a slice of that node's
+// buf directly rather than copying: data.buf is never mutated after
+// it's filled, so aliasing it is safe and lets callers like GetBytes
+// avoid an allocation for the common single-node case.
+//
+// It returns ErrCommitted instead if any node spanning start..end has
+// already been reclaimed by Commit: that node's buf is nil, so both
+// the direct-slice and the copying path would otherwise panic (or, for
+// a reclaimed node in the middle of a multi-node span, silently copy
+// zero bytes and return a truncated result with no error at all).
+func keepBytes(start, end State) ([]byte, error) {
+	if start.data.reclaimed || end.data.reclaimed {
+		return nil, ErrCommitted
+	}
 	if start.data == end.data {
-		result := start.data.buf[start.datap:end.datap]
-		clone := make([]byte, len(result))
-		copy(clone, result)
-		return clone
+		return start.data.buf[start.datap:end.datap], nil
 	}
 
 	result := make([]byte, end.offset-start.offset)
 	resultp := copy(result, start.data.buf[start.datap:])
 	current := start.nextDataState()
 	for current.data != end.data {
+		if current.data.reclaimed {
+			return nil, ErrCommitted
+		}
 		n := copy(result[resultp:], current.data.buf)
 		resultp += n
 		current = current.nextDataState()
 	}
 	copy(result[resultp:], end.data.buf[:end.datap])
-	return result
+	return result, nil
 }
 
 // data is a node in a linked list of []byte.
@@ -192,17 +338,90 @@ type data struct {
 	err  error
 	buf  []byte
 	next *data
+
+	start     int64 // absolute stream offset of buf[0]
+	end       int64 // absolute stream offset just past buf, fixed even after reclaim
+	reclaimed bool  // true once a streamOwner has freed buf
+
+	// posCached, lineAtStart, and colAtStart cache the (0-based) line
+	// and column at start, the first time a State crosses into this
+	// node via nextDataState. SeekOffset uses this to rebuild Line and
+	// Column without rescanning the whole chain from the beginning.
+	posCached   bool
+	lineAtStart int
+	colAtStart  int
+}
+
+// ensureNext lazily pulls the next chunk from d.r and wires up
+// d.next, the same way Rune and Byte do when they run out of
+// buffered data. It's a no-op if d.next is already set or d.r has
+// already hit EOF.
+func (d *data) ensureNext() {
+	if d.r == nil || d.next != nil {
+		return
+	}
+	d.next = newDataReaderSize(d.r, cap(d.buf))
+	d.next.start = d.start + int64(len(d.buf))
+	d.next.end = d.next.start + int64(len(d.next.buf))
+}
+
+// streamOwner is shared by every State derived from a single
+// NewStreamingStateReader call. head is the earliest *data node that
+// hasn't been reclaimed; committed is the highest offset passed to
+// Commit so far (-1 if Commit has never run).
+type streamOwner struct {
+	head      *data
+	committed int64
+}
+
+// commit advances head past any node wholly before offset, freeing
+// its buf so the garbage collector can reclaim the underlying bytes
+// even if some earlier State value is still holding a pointer to the
+// node itself. Before reclaiming a node it makes sure a successor is
+// wired up (ensureNext), so a State sitting exactly at that node's
+// end can still move on to more data: its reclaimed check compares
+// against the node's fixed start/end rather than its now-nil buf, so
+// landing on a clean boundary still falls through to that successor
+// instead of being mistaken for stale, mid-node access.
+//
+// line and column are the position at offset (the committing State's
+// own Line/Column): when committing lands exactly on a node boundary,
+// ensureNext wires up a successor that no State has read into yet, so
+// nextDataState never gets a chance to cache its starting position.
+// Since that successor's start is exactly offset, line and column are
+// already its correct lineAtStart/colAtStart, so commit stamps them
+// directly instead of leaving posCached false for SeekOffset to trip
+// over later.
+func (o *streamOwner) commit(offset int64, line, column int) {
+	if offset <= o.committed {
+		return
+	}
+	o.committed = offset
+	for o.head != nil && o.head.end <= offset {
+		o.head.ensureNext()
+		if o.head.end == offset && o.head.next != nil && !o.head.next.posCached {
+			o.head.next.posCached = true
+			o.head.next.lineAtStart = line
+			o.head.next.colAtStart = column
+		}
+		o.head.buf = nil
+		o.head.reclaimed = true
+		o.head = o.head.next
+	}
 }
 
 func newDataString(s string) *data {
+	buf := []byte(s)
 	return &data{
-		buf: []byte(s),
+		buf: buf,
+		end: int64(len(buf)),
 	}
 }
 
 func newDataBytes(b []byte) *data {
 	d := &data{
 		buf: make([]byte, len(b)),
+		end: int64(len(b)),
 	}
 	copy(d.buf, b)
 	return d
@@ -210,28 +429,78 @@ func newDataBytes(b []byte) *data {
 
 const minDataSize = 8
 
+// inMemoryReader is satisfied by *strings.Reader and *bytes.Reader:
+// both already hold their entire contents in memory, so there's no
+// benefit to pulling them through Read in size-limited chunks.
+type inMemoryReader interface {
+	io.ReaderAt
+	Len() int
+	Size() int64
+}
+
 func newDataReaderSize(r io.Reader, size int) *data {
-	// TODO both: only optimize when the size isn't specified.
-	if _, ok := r.(*strings.Reader); ok {
-	}
-	if _, ok := r.(*bytes.Reader); ok {
+	if imr, ok := r.(inMemoryReader); ok {
+		// Build a single node holding (a copy of) the unread portion
+		// in one shot, instead of walking it through Read a chunk at
+		// a time. This also means a rune can never be split across a
+		// data node boundary for these sources.
+		return newDataInMemory(imr)
 	}
 
 	d := &data{
 		r:   r,
 		buf: make([]byte, size),
 	}
-	// TODO make more robust, check for empty reads in loop.
-	n, err := r.Read(d.buf)
+	n, err := readWithRetry(r, d.buf)
 	d.buf = d.buf[:n]
-	if err == io.EOF {
-		err = nil
+	if err != nil {
+		// Any error, not just io.EOF, means r.Read won't be tried
+		// again: leaving d.r set for e.g. io.ErrUnexpectedEOF or a
+		// wrapped EOF would make ensureNext keep calling back in here
+		// on every commit/reclaim pass, each time producing another
+		// zero-length terminal node without ever nil-ing out d.r.
 		d.r = nil
+		if err == io.EOF {
+			err = nil
+		}
 	}
 	d.err = err
+	d.end = int64(n)
 	return d
 }
 
+// maxEmptyReads bounds readWithRetry's tolerance for a reader that
+// legally (if unhelpfully) returns (0, nil) instead of blocking or
+// erroring, the same defense bufio.Reader uses.
+const maxEmptyReads = 100
+
+// readWithRetry calls r.Read until it makes progress, returns an
+// error, or has read zero bytes maxEmptyReads times in a row: without
+// this, a reader that returns (0, nil) would make ensureNext spin
+// forever trying to pull a next chunk that never arrives.
+func readWithRetry(r io.Reader, buf []byte) (int, error) {
+	for i := 0; i < maxEmptyReads; i++ {
+		n, err := r.Read(buf)
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+	return 0, io.ErrNoProgress
+}
+
+func newDataInMemory(r inMemoryReader) *data {
+	n := r.Len()
+	if n == 0 {
+		return &data{}
+	}
+	buf := make([]byte, n)
+	pos := r.Size() - int64(n)
+	if _, err := r.ReadAt(buf, pos); err != nil && err != io.EOF {
+		return &data{err: err}
+	}
+	return &data{buf: buf, end: int64(len(buf))}
+}
+
 // ---------------------------------------------
 
 type Position struct {
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"io"
 	mathrand "math/rand/v2"
+	"runtime"
 	"slices"
 	"strings"
 	"testing"
@@ -77,7 +78,7 @@ func TestState_Rune_ConsumesAllFromByteData(t *testing.T) {
 
 func TestState_Rune_ConsumesStateEndingAtDataNode8(t *testing.T) {
 	const data = "aaaaBBBB"
-	s := NewStateReaderSize(strings.NewReader(data), 8)
+	s := NewStateReaderSize(&plainReader{strings.NewReader(data)}, 8)
 
 	result, err := allStateRunes(s)
 	if err != io.EOF {
@@ -90,7 +91,7 @@ func TestState_Rune_ConsumesStateEndingAtDataNode8(t *testing.T) {
 
 func TestState_Rune_ConsumesStateEndingAtDataNode32(t *testing.T) {
 	const data = "aaaaBBBBccccDDDDeeeeFFFFggggHHHH"
-	s := NewStateReaderSize(strings.NewReader(data), 8)
+	s := NewStateReaderSize(&plainReader{strings.NewReader(data)}, 8)
 
 	result, err := allStateRunes(s)
 	if err != io.EOF {
@@ -103,7 +104,7 @@ func TestState_Rune_ConsumesStateEndingAtDataNode32(t *testing.T) {
 
 func TestState_Rune_ConsumesStateAcrossDataNodes(t *testing.T) {
 	const data = "aaaaBBBBccccDDDDee"
-	s := NewStateReaderSize(strings.NewReader(data), 8)
+	s := NewStateReaderSize(&plainReader{strings.NewReader(data)}, 8)
 
 	result, err := allStateRunes(s)
 	if err != io.EOF {
@@ -116,7 +117,7 @@ func TestState_Rune_ConsumesStateAcrossDataNodes(t *testing.T) {
 
 func TestState_Rune_ConsumesStateAcrossDataNodesRunesMatchingDataBoundary(t *testing.T) {
 	const data = "\u0081\u0082\u0083\u0084\u0085\u0086\u0087\u0088\u0089\u008a"
-	s := NewStateReaderSize(strings.NewReader(data), 8)
+	s := NewStateReaderSize(&plainReader{strings.NewReader(data)}, 8)
 
 	result, err := allStateRunes(s)
 	if err != io.EOF {
@@ -129,7 +130,7 @@ func TestState_Rune_ConsumesStateAcrossDataNodesRunesMatchingDataBoundary(t *tes
 
 func TestState_Rune_ConsumesRuneSplitAcrossDataBoundary(t *testing.T) {
 	const data = "1234567∞"
-	s := NewStateReaderSize(strings.NewReader(data), 8)
+	s := NewStateReaderSize(&plainReader{strings.NewReader(data)}, 8)
 
 	result, err := allStateRunes(s)
 	if err != io.EOF {
@@ -161,7 +162,7 @@ func TestState_Rune_ErrorsRuneErrorWithInvalidRuneEncodingAcrossDataBoundary(t *
 	data := "1234567∞"
 	data = data[:9] + "acbd"
 
-	s := NewStateReaderSize(strings.NewReader(data), 8)
+	s := NewStateReaderSize(&plainReader{strings.NewReader(data)}, 8)
 	_, err := allStateRunes(s)
 
 	var want RuneError
@@ -173,6 +174,35 @@ func TestState_Rune_ErrorsRuneErrorWithInvalidRuneEncodingAcrossDataBoundary(t *
 	}
 }
 
+func TestState_Rune_StringsReaderIgnoresSizeAndNeverSplitsARune(t *testing.T) {
+	// Regardless of the requested chunk size, *strings.Reader is
+	// loaded as a single node, so a rune that would otherwise straddle
+	// a data boundary decodes in one piece.
+	const data = "1234567∞"
+	s := NewStateReaderSize(strings.NewReader(data), 8)
+
+	result, err := allStateRunes(s)
+	if err != io.EOF {
+		t.Fatal(err)
+	}
+	if string(result) != data {
+		t.Fatal(result)
+	}
+}
+
+func TestState_Rune_BytesReaderIgnoresSizeAndNeverSplitsARune(t *testing.T) {
+	const data = "1234567∞"
+	s := NewStateReaderSize(bytes.NewReader([]byte(data)), 8)
+
+	result, err := allStateRunes(s)
+	if err != io.EOF {
+		t.Fatal(err)
+	}
+	if string(result) != data {
+		t.Fatal(result)
+	}
+}
+
 func TestState_Rune_EventuallyConsumesEOFWithEmptyLastDataNode(t *testing.T) {
 	s := NewStateReader(&fullThenZeroReader{5})
 	_, err := allStateRunes(s)
@@ -193,7 +223,7 @@ func TestState_Rune_DuplicateFullReadsReturnTheSameResult(t *testing.T) {
 		data = append(data, randomValidNonErrorUTF8(rand)...)
 	}
 
-	s := NewStateReaderSize(bytes.NewReader(data), 13)
+	s := NewStateReaderSize(&plainReader{bytes.NewReader(data)}, 13)
 
 	result1, err := allStateRunes(s)
 	if err != io.EOF {
@@ -296,7 +326,7 @@ func TestState_Byte_ConsumesAllFromByteData(t *testing.T) {
 
 func TestState_Byte_ConsumesStateEndingAtDataNode8(t *testing.T) {
 	const data = "aaaaBBBB"
-	s := NewStateReaderSize(strings.NewReader(data), 8)
+	s := NewStateReaderSize(&plainReader{strings.NewReader(data)}, 8)
 
 	result, err := allStateBytes(s)
 	if err != io.EOF {
@@ -309,7 +339,7 @@ func TestState_Byte_ConsumesStateEndingAtDataNode8(t *testing.T) {
 
 func TestState_Byte_ConsumesStateEndingAtDataNode32(t *testing.T) {
 	const data = "aaaaBBBBccccDDDDeeeeFFFFggggHHHH"
-	s := NewStateReaderSize(strings.NewReader(data), 8)
+	s := NewStateReaderSize(&plainReader{strings.NewReader(data)}, 8)
 
 	result, err := allStateBytes(s)
 	if err != io.EOF {
@@ -322,7 +352,7 @@ func TestState_Byte_ConsumesStateEndingAtDataNode32(t *testing.T) {
 
 func TestState_Byte_ConsumesStateAcrossDataNodes(t *testing.T) {
 	const data = "aaaaBBBBccccDDDDee"
-	s := NewStateReaderSize(strings.NewReader(data), 8)
+	s := NewStateReaderSize(&plainReader{strings.NewReader(data)}, 8)
 
 	result, err := allStateBytes(s)
 	if err != io.EOF {
@@ -360,6 +390,405 @@ func TestState_Byte_DuplicateFullReadsReturnTheSameResult(t *testing.T) {
 	}
 }
 
+// plainReader hides a reader's concrete type behind io.Reader so
+// tests can force the generic chunked-read path in newDataReaderSize
+// even when wrapping a *strings.Reader or *bytes.Reader.
+type plainReader struct {
+	r io.Reader
+}
+
+func (p *plainReader) Read(b []byte) (int, error) {
+	return p.r.Read(b)
+}
+
+func TestCommit_OldStateErrorsAfterReclaim(t *testing.T) {
+	const data = "abcdefghijklmnop" // 16 bytes, 2 nodes of 8 with size 4
+	s := NewStreamingStateReader(&plainReader{strings.NewReader(data)}, 4)
+	old := s
+
+	commitFirstNode := Commit(func(s State) (Empty, State, error) {
+		for i := 0; i < 8; i++ {
+			var err error
+			if _, s, err = s.Byte(); err != nil {
+				return Empty{}, s, err
+			}
+		}
+		return Empty{}, s, nil
+	})
+	if _, _, err := commitFirstNode(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := old.Byte(); !errors.Is(err, ErrCommitted) {
+		t.Fatalf("err = %v WANT ErrCommitted", err)
+	}
+}
+
+func TestGetString_ErrorsWhenAnInnerCommitReclaimsItsSpan(t *testing.T) {
+	const data = "abcdefghijklmnop" // 16 bytes, 2 nodes of 8 with size 4
+	s := NewStreamingStateReader(&plainReader{strings.NewReader(data)}, 4)
+
+	p := GetString(Sequence2(
+		GetString(Commit(ExactBytes([]byte(data[:8])))),
+		GetString(ExactBytes([]byte(data[8:]))),
+		func(a, b string) (string, error) { return a + b, nil },
+	))
+
+	if _, _, err := p(s); !errors.Is(err, ErrCommitted) {
+		t.Fatalf("err = %v WANT ErrCommitted", err)
+	}
+}
+
+func TestCommit_NoopWithoutStreamingOwner(t *testing.T) {
+	s := NewStateString("abc")
+	p := Commit(ExactString("ab"))
+
+	_, _, err := p(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// zeroByteReader is an io.Reader standing in for a slow, effectively
+// unbounded stream: it yields n zero bytes total before EOF.
+type zeroByteReader struct {
+	remaining int
+}
+
+func (r *zeroByteReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		return 0, io.EOF
+	}
+	n := len(p)
+	if n > r.remaining {
+		n = r.remaining
+	}
+	for i := range p[:n] {
+		p[i] = 0
+	}
+	r.remaining -= n
+	return n, nil
+}
+
+// unexpectedEOFReader yields its data and then reports exhaustion
+// with io.ErrUnexpectedEOF instead of io.EOF, the way a truncated
+// network read or a strict framing reader would.
+type unexpectedEOFReader struct {
+	data []byte
+}
+
+func (r *unexpectedEOFReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestNewStreamingStateReader_CommitAtEOFDoesNotSpinOnANonEOFError(t *testing.T) {
+	const data = "abcdefgh"
+	s := NewStreamingStateReader(&unexpectedEOFReader{data: []byte(data)}, 4)
+
+	commitEight := Commit(func(s State) (Empty, State, error) {
+		for i := 0; i < len(data); i++ {
+			var err error
+			if _, s, err = s.Byte(); err != nil {
+				return Empty{}, s, err
+			}
+		}
+		return Empty{}, s, nil
+	})
+	if _, _, err := commitEight(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewStreamingStateReader_CommitBoundsMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("feeds tens of megabytes through a reader")
+	}
+
+	const chunkSize = 4096
+	const chunks = 8192 // 32MiB fed through the reader.
+	const total = chunkSize * chunks
+
+	commitNode := Commit(func(s State) (Empty, State, error) {
+		return Empty{}, s, nil
+	})
+
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	s := NewStreamingStateReader(&zeroByteReader{remaining: total}, chunkSize)
+	count := 0
+	for {
+		_, next, err := s.Byte()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		s = next
+		count++
+		if count%chunkSize == 0 {
+			var err error
+			if _, s, err = commitNode(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if count != total {
+		t.Fatalf("count = %v WANT %v", count, total)
+	}
+
+	runtime.GC()
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+
+	// Without reclaiming, every one of the 8192 chunk-sized nodes
+	// would still be reachable: ~32MiB. Committing as we go should
+	// keep only a handful of their buffers alive at once.
+	grew := int64(after.HeapAlloc) - int64(before.HeapAlloc)
+	const wantUnder = total / 4
+	if grew > wantUnder {
+		t.Fatalf("heap grew by %d bytes after feeding %d, want < %d (Commit is not reclaiming nodes)", grew, total, wantUnder)
+	}
+}
+
+func TestState_PeekRune_DoesNotAdvance(t *testing.T) {
+	s := NewStateString("abc")
+
+	r, err := s.PeekRune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != 'a' {
+		t.Fatalf("r = %q WANT %q", r, 'a')
+	}
+
+	// s itself must be unaffected: reading again gets the same rune.
+	r, _, err = s.Rune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != 'a' {
+		t.Fatalf("r = %q WANT %q", r, 'a')
+	}
+}
+
+func TestState_PeekRune_AcrossDataNodes(t *testing.T) {
+	const data = "abcdefghijklmnop" // 2 nodes of 8 with size 8
+	s := NewStateReaderSize(&plainReader{strings.NewReader(data)}, 8)
+
+	var err error
+	for i := 0; i < 8; i++ {
+		_, s, err = s.Byte()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// s is now at the start of the second node.
+
+	r, err := s.PeekRune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != 'i' {
+		t.Fatalf("r = %q WANT %q", r, 'i')
+	}
+}
+
+func TestState_PeekBytes_DoesNotAdvance(t *testing.T) {
+	s := NewStateString("abcdef")
+
+	peeked, err := s.PeekBytes(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(peeked) != "abc" {
+		t.Fatalf("peeked = %q WANT %q", peeked, "abc")
+	}
+
+	_, next, err := s.Byte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.offset != 1 {
+		t.Fatalf("next.offset = %v WANT 1", next.offset)
+	}
+}
+
+func TestState_PeekBytes_AcrossDataNodes(t *testing.T) {
+	const data = "abcdefghijklmnop" // 2 nodes of 8 with size 8
+	s := NewStateReaderSize(&plainReader{strings.NewReader(data)}, 8)
+
+	peeked, err := s.PeekBytes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(peeked) != "abcdefghij" {
+		t.Fatalf("peeked = %q WANT %q", peeked, "abcdefghij")
+	}
+}
+
+func TestState_PeekBytes_ErrorsWithPartialResultOnEOF(t *testing.T) {
+	s := NewStateString("ab")
+
+	peeked, err := s.PeekBytes(5)
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("err = %v WANT io.EOF", err)
+	}
+	if string(peeked) != "ab" {
+		t.Fatalf("peeked = %q WANT %q", peeked, "ab")
+	}
+}
+
+func TestState_SeekOffset_WithinSameNode(t *testing.T) {
+	s := NewStateString("abcdef")
+	_, mid, err := s.Byte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, mid, err = mid.Byte()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	back, err := mid.SeekOffset(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _, err := back.Byte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b != 'a' {
+		t.Fatalf("b = %q WANT %q", b, 'a')
+	}
+}
+
+func TestState_SeekOffset_AcrossDataNodes(t *testing.T) {
+	const data = "abcdefghijklmnop" // 2 nodes of 8 with size 8
+	s := NewStateReaderSize(&plainReader{strings.NewReader(data)}, 8)
+
+	var last State
+	for i := 0; i < 10; i++ {
+		var err error
+		_, s, err = s.Byte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = s
+	}
+	// last is positioned after reading "abcdefghij", in the second node.
+
+	back, err := last.SeekOffset(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _, err := back.Byte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b != 'b' {
+		t.Fatalf("b = %q WANT %q", b, 'b')
+	}
+}
+
+func TestState_SeekOffset_RebuildsLineAndColumn(t *testing.T) {
+	const data = "ab\ncd\nefgh\nij\nkl" // 2 nodes of 8 with size 8
+	s := NewStateReaderSize(&plainReader{strings.NewReader(data)}, 8)
+
+	var last State
+	for i := 0; i < 10; i++ {
+		var err error
+		_, s, err = s.Byte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = s
+	}
+	// last is positioned after reading "ab\ncd\nefgh", in the second node.
+
+	// Offset 10 is the '\n' right after "gh", in the second node: two
+	// lines consumed in the first node, plus "efgh" ("ef" at the end of
+	// the first node, "gh" at the start of the second).
+	seeked, err := last.SeekOffset(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seeked.Line() != 3 || seeked.Column() != 5 {
+		t.Fatalf("Line() = %v, Column() = %v WANT 3, 5", seeked.Line(), seeked.Column())
+	}
+}
+
+func TestState_SeekOffset_ErrorsPastEndOfInput(t *testing.T) {
+	s := NewStateString("ab")
+
+	_, err := s.SeekOffset(10)
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("err = %v WANT io.EOF", err)
+	}
+}
+
+func TestState_SeekOffset_ErrorsIntoReclaimedNode(t *testing.T) {
+	const data = "abcdefghijklmnop" // 16 bytes, 2 nodes of 8 with size 4
+	s := NewStreamingStateReader(&plainReader{strings.NewReader(data)}, 4)
+	old := s
+
+	commitFirstNode := Commit(func(s State) (Empty, State, error) {
+		for i := 0; i < 8; i++ {
+			var err error
+			if _, s, err = s.Byte(); err != nil {
+				return Empty{}, s, err
+			}
+		}
+		return Empty{}, s, nil
+	})
+	if _, _, err := commitFirstNode(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := old.SeekOffset(0); !errors.Is(err, ErrCommitted) {
+		t.Fatalf("err = %v WANT ErrCommitted", err)
+	}
+}
+
+func TestState_SeekOffset_AfterCommitExactlyOnNodeBoundary(t *testing.T) {
+	const data = "ab\ncdefghij" // first node "ab\ncdefg" (8 bytes: minDataSize floors the requested size)
+	s := NewStreamingStateReader(&plainReader{strings.NewReader(data)}, 4)
+
+	commitFirstNode := Commit(func(s State) (Empty, State, error) {
+		for i := 0; i < 8; i++ {
+			var err error
+			if _, s, err = s.Byte(); err != nil {
+				return Empty{}, s, err
+			}
+		}
+		return Empty{}, s, nil
+	})
+	_, committed, err := commitFirstNode(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// committed sits exactly on the node boundary ensureNext wires up
+	// for Commit's reclaim, not one any State has read into yet.
+	if committed.Line() != 2 || committed.Column() != 6 {
+		t.Fatalf("Line() = %v, Column() = %v WANT 2, 6", committed.Line(), committed.Column())
+	}
+
+	seeked, err := committed.SeekOffset(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seeked.Line() != 2 || seeked.Column() != 6 {
+		t.Fatalf("Line() = %v, Column() = %v WANT 2, 6", seeked.Line(), seeked.Column())
+	}
+}
+
 type fullThenZeroReader struct {
 	count int
 }
@@ -374,6 +803,16 @@ func (r *fullThenZeroReader) Read(p []byte) (n int, err error) {
 	return
 }
 
+func randomValidNonErrorUTF8(rand *mathrand.Rand) []byte {
+	r := rune(rand.IntN(utf8.MaxRune))
+	for !utf8.ValidRune(r) || r == utf8.RuneError {
+		r = rune(rand.IntN(utf8.MaxRune))
+	}
+	buf := make([]byte, utf8.RuneLen(r))
+	utf8.EncodeRune(buf, r)
+	return buf
+}
+
 // TODO re-reading state gets us to already next and is the same as original read.
 // TODO additionally, we should get the same errors with rune reading after multiple reads.
 
@@ -400,13 +839,3 @@ func allStateRunes(s State) ([]rune, error) {
 	}
 	return result, err
 }
-
-func randomValidNonErrorUTF8(rand *mathrand.Rand) []byte {
-	r := rune(rand.IntN(utf8.MaxRune))
-	for !utf8.ValidRune(r) || r == utf8.RuneError {
-		r = rune(rand.IntN(utf8.MaxRune))
-	}
-	buf := make([]byte, utf8.RuneLen(r))
-	utf8.EncodeRune(buf, r)
-	return buf
-}